@@ -0,0 +1,231 @@
+package xsdgen
+
+import (
+	"encoding/xml"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"aqwari.net/xml/internal/gen"
+	"aqwari.net/xml/xsd"
+)
+
+// ChoiceAsUnion causes any *xsd.ComplexType containing an xs:choice group
+// to be generated as a discriminated union, rather than a struct with all
+// branches declared as optional fields. A FooKind enum and a Which field
+// record which branch is present, and the generated MarshalXML and
+// UnmarshalXML methods encode and decode only that branch. A choice with
+// maxOccurs greater than one is generated as a slice of a separately
+// named union type (FooItem, for a choice repeated under Foo), since the
+// repeated type itself can't also be the element type it repeats. A
+// complex type that also declares attributes, or elements outside the
+// choice group, is generated as an ordinary struct.
+func ChoiceAsUnion() Option {
+	return func(cfg *Config) Option {
+		prevFlag := cfg.choiceAsUnion
+		prevPost := cfg.postprocessType
+		cfg.choiceAsUnion = true
+		cfg.postprocessType = func(s spec) spec {
+			if prevPost != nil {
+				s = prevPost(s)
+			}
+			return cfg.choiceUnion(s)
+		}
+		return func(cfg *Config) Option {
+			cfg.choiceAsUnion = prevFlag
+			cfg.postprocessType = prevPost
+			return ChoiceAsUnion()
+		}
+	}
+}
+
+// A choiceBranch describes one alternative of an xs:choice group, as
+// threaded through from xsd parsing to code generation.
+type choiceBranch struct {
+	// XML name of the element naming this branch.
+	Name xml.Name
+	// Go identifier naming this branch's FooKind value, e.g. "A"
+	// for a FooKindA constant.
+	Kind string
+	// Go identifier of the field holding this branch's value.
+	Field string
+	// The branch's declared type.
+	Type xsd.Type
+}
+
+// unionSpec carries the information needed to generate the Kind enum and
+// Marshal/Unmarshal methods for a choice-as-union type.
+type unionSpec struct {
+	branches  []choiceBranch
+	maxOccurs int
+}
+
+// detectChoiceUnion reports whether s's underlying *xsd.ComplexType
+// contains an xs:choice group, and if so builds the unionSpec describing
+// its branches. The xsd parser marks every element belonging to an
+// xs:choice with Choice true, so a type qualifies as a choice candidate
+// only when it has two or more elements and every one of them carries that
+// marker; a type with any ordinary xs:sequence element, or any attribute,
+// is left as it is.
+//
+// The choice group's own maxOccurs (<xs:choice maxOccurs="...">) is
+// propagated by the parser to every branch element alike, so the group
+// repeats only when every branch element is Plural; a single branch
+// element being Plural on its own describes that element repeating
+// within one occurrence of the choice, not the choice itself repeating.
+func (cfg *Config) detectChoiceUnion(s spec) (unionSpec, bool) {
+	t, ok := s.xsdType.(*xsd.ComplexType)
+	if !ok || len(t.Attributes) > 0 {
+		return unionSpec{}, false
+	}
+	var branches []xsd.Element
+	for _, el := range t.Elements {
+		if !el.Choice {
+			return unionSpec{}, false
+		}
+		branches = append(branches, el)
+	}
+	if len(branches) < 2 {
+		return unionSpec{}, false
+	}
+	var union unionSpec
+	allPlural := true
+	for _, el := range branches {
+		name := cfg.public(el.Name)
+		union.branches = append(union.branches, choiceBranch{
+			Name:  el.Name,
+			Kind:  name,
+			Field: name,
+			Type:  el.Type,
+		})
+		if !el.Plural {
+			allPlural = false
+		}
+	}
+	if allPlural {
+		union.maxOccurs = 2
+	} else {
+		union.maxOccurs = 1
+	}
+	return union, true
+}
+
+// choiceUnion rewrites a spec describing a choice group into a
+// discriminated union, when ChoiceAsUnion is enabled.
+func (cfg *Config) choiceUnion(s spec) spec {
+	if !cfg.choiceAsUnion {
+		return s
+	}
+	union, ok := cfg.detectChoiceUnion(s)
+	if !ok {
+		return s
+	}
+	cfg.debugf("generating discriminated union %s for choice group with %d branches", s.name, len(union.branches))
+
+	// When the choice group repeats, the union struct is generated under
+	// a distinct name (FooItem) and s.name becomes a slice of it; s.name
+	// can't name both the slice and the struct it holds.
+	itemName := s.name
+	if union.maxOccurs > 1 {
+		itemName = s.name + "Item"
+	}
+
+	kindName := itemName + "Kind"
+	var kindSrc strings.Builder
+	fmt.Fprintf(&kindSrc, "package p\ntype %s int\nconst (\n", kindName)
+	fields := []*ast.Field{
+		{
+			Names: []*ast.Ident{ast.NewIdent("Which")},
+			Type:  ast.NewIdent(kindName),
+		},
+	}
+	var marshalCases, unmarshalCases []string
+	for i, b := range union.branches {
+		constName := kindName + b.Kind
+		if i == 0 {
+			fmt.Fprintf(&kindSrc, "\t%s = iota\n", constName)
+		} else {
+			fmt.Fprintf(&kindSrc, "\t%s\n", constName)
+		}
+		fields = append(fields, &ast.Field{
+			Names: []*ast.Ident{ast.NewIdent(b.Field)},
+			Type:  &ast.StarExpr{X: ast.NewIdent(cfg.typeName(xsd.XMLName(b.Type)))},
+		})
+		marshalCases = append(marshalCases, fmt.Sprintf(`
+			case %s:
+				start.Name = xml.Name{%q, %q}
+				return e.EncodeElement(v.%s, start)`,
+			constName, b.Name.Space, b.Name.Local, b.Field))
+		unmarshalCases = append(unmarshalCases, fmt.Sprintf(`
+			case xml.Name{%q, %q}:
+				v.Which = %s
+				v.%s = new(%s)
+				return d.DecodeElement(v.%s, &start)`,
+			b.Name.Space, b.Name.Local, constName, b.Field, cfg.typeName(xsd.XMLName(b.Type)), b.Field))
+	}
+	kindSrc.WriteString(")\n")
+	kindSrc.WriteString("func (k " + kindName + ") String() string {\n\tswitch k {\n")
+	for _, b := range union.branches {
+		fmt.Fprintf(&kindSrc, "\tcase %s%s:\n\t\treturn %q\n", kindName, b.Kind, b.Kind)
+	}
+	kindSrc.WriteString("\t}\n\treturn \"unknown\"\n}\n")
+
+	kindFile, err := parser.ParseFile(token.NewFileSet(), kindName+".go", kindSrc.String(), 0)
+	if err != nil {
+		cfg.logf("error generating %s enum: %v", kindName, err)
+		return s
+	}
+
+	marshal, err := gen.Func("MarshalXML").
+		Receiver("v "+itemName).
+		Args("e *xml.Encoder", "start xml.StartElement").
+		Returns("error").
+		Body("%s", `
+			switch v.Which {
+			`+strings.Join(marshalCases, "\n")+`
+			}
+			return fmt.Errorf("%s: no branch set", "`+itemName+`")
+		`).Decl()
+	if err != nil {
+		cfg.logf("error generating MarshalXML method of %s: %v", itemName, err)
+		return s
+	}
+
+	unmarshal, err := gen.Func("UnmarshalXML").
+		Receiver("v *"+itemName).
+		Args("d *xml.Decoder", "start xml.StartElement").
+		Returns("error").
+		Body("%s", `
+			switch start.Name {
+			`+strings.Join(unmarshalCases, "\n")+`
+			}
+			return d.Skip()
+		`).Decl()
+	if err != nil {
+		cfg.logf("error generating UnmarshalXML method of %s: %v", itemName, err)
+		return s
+	}
+
+	itemStruct := &ast.StructType{Fields: &ast.FieldList{List: fields}}
+	s.decls = append(s.decls, kindFile.Decls...)
+
+	if union.maxOccurs > 1 {
+		// itemName names a separate type from s.name, so its type
+		// declaration and methods are appended as plain decls rather
+		// than s.methods, which belong to s.name.
+		itemType := &ast.GenDecl{
+			Tok: token.TYPE,
+			Specs: []ast.Spec{
+				&ast.TypeSpec{Name: ast.NewIdent(itemName), Type: itemStruct},
+			},
+		}
+		s.decls = append(s.decls, itemType, marshal, unmarshal)
+		s.expr = &ast.ArrayType{Elt: ast.NewIdent(itemName)}
+	} else {
+		s.expr = itemStruct
+		s.methods = append(s.methods, marshal, unmarshal)
+	}
+	return s
+}
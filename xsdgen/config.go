@@ -34,6 +34,25 @@ type Config struct {
 	elemNameTransform nameTransform
 	attrNameTransform nameTransform
 	allNameTransform  nameTransform
+	// If set, struct fields for a complex type's elements and
+	// attributes are named after the element/attribute itself,
+	// rather than the type it references.
+	useFieldNames bool
+	// Builds the error returned by generated Validate methods; see
+	// the EmitValidators and ValidatorErrorFormat Options.
+	validatorError ValidatorErrorFunc
+	// True once the digitCount/fractionDigitCount helpers used by
+	// generated totalDigits/fractionDigits checks have been emitted.
+	digitHelpersEmitted bool
+	// If set, xs:choice groups are generated as discriminated
+	// unions; see the ChoiceAsUnion Option.
+	choiceAsUnion bool
+	// The ordered set of named passes that make up cfg's code
+	// generation behavior; see the Pipeline type.
+	pipeline Pipeline
+	// Additional struct tags to emit for generated fields, beyond
+	// the xml tag every field already gets; see ExtraStructTags.
+	tagEmitters []TagEmitter
 }
 
 type nameTransform func(xml.Name) string
@@ -259,33 +278,79 @@ func ProcessTypes(fn func(xsd.Schema, xsd.Type) xsd.Type) Option {
 
 // The Option HandleSOAPArrayType adds a special-case pre-processing step to
 // xsdgen that parses the wsdl:arrayType attribute of a SOAP array declaration
-// and changes the underlying base type to match.
+// and changes the underlying base type to match. Internally, it registers
+// the named "soap-array-preprocess" Pass, and wires cfg.preprocessType -- the
+// hook the generator calls for every type -- to run the Pipeline's PreType
+// passes.
 func HandleSOAPArrayType() Option {
 	return func(cfg *Config) Option {
-		prev := cfg.preprocessType
-		return replacePreprocessType(&cfg.preprocessType, func(s xsd.Schema, t xsd.Type) xsd.Type {
-			if prev != nil {
-				t = prev(s, t)
+		prevPass := AddPass(soapArrayPreprocessPass)(cfg)
+		prevPreprocess := cfg.preprocessType
+		cfg.preprocessType = func(s xsd.Schema, t xsd.Type) xsd.Type {
+			if prevPreprocess != nil {
+				t = prevPreprocess(s, t)
 			}
-			return cfg.parseSOAPArrayType(s, t)
-		})(cfg)
+			return cfg.runPreTypePasses(s, t)
+		}
+		return func(cfg *Config) Option {
+			cfg.preprocessType = prevPreprocess
+			return prevPass(cfg)
+		}
 	}
 }
 
 // The Option SOAPArrayAsSlice converts complex types with a single, plural
-// element to a slice of the element's type.
+// element to a slice of the element's type. Internally, it registers the
+// named "soap-array-postprocess" Pass, and wires cfg.postprocessType -- the
+// hook the generator calls for every generated spec -- to run the
+// Pipeline's PostSpec passes.
 func SOAPArrayAsSlice() Option {
 	return func(cfg *Config) Option {
-		prev := cfg.postprocessType
-		return replacePostprocessType(&cfg.postprocessType, func(s spec) spec {
-			if prev != nil {
-				s = prev(s)
+		prevPass := AddPass(soapArrayPostprocessPass)(cfg)
+		prevPostprocess := cfg.postprocessType
+		cfg.postprocessType = func(s spec) spec {
+			if prevPostprocess != nil {
+				s = prevPostprocess(s)
 			}
-			return cfg.soapArrayToSlice(s)
-		})(cfg)
+			return cfg.runPostSpecPasses(nil, s)
+		}
+		return func(cfg *Config) Option {
+			cfg.postprocessType = prevPostprocess
+			return prevPass(cfg)
+		}
 	}
 }
 
+func setUseFieldNames(v bool) Option {
+	return func(cfg *Config) Option {
+		prev := cfg.useFieldNames
+		cfg.useFieldNames = v
+		return setUseFieldNames(prev)
+	}
+}
+
+// UseFieldNames specifies that the struct fields generated for a
+// *xsd.ComplexType's elements and attributes should be named after the
+// element or attribute's own XML local name (run through ReplaceAllNames
+// and title-cased), rather than the name of the type it references. This
+// is useful when several elements share a common, or anonymous, type; it
+// produces field names like Address or PhoneNumber instead of names
+// derived from the underlying type.
+func UseFieldNames() Option {
+	return setUseFieldNames(true)
+}
+
+// fieldName returns the Go field name to use for an element or attribute.
+// When UseFieldNames is in effect, it is derived from the provided XML
+// name; otherwise fallback, which callers pass as the name of the
+// element/attribute's type, is returned unchanged.
+func (cfg *Config) fieldName(name xml.Name, fallback string) string {
+	if !cfg.useFieldNames {
+		return fallback
+	}
+	return cfg.public(name)
+}
+
 func (cfg *Config) filterFields(t *xsd.ComplexType) ([]xsd.Attribute, []xsd.Element) {
 	var (
 		elements   []xsd.Element
@@ -345,20 +410,20 @@ func (cfg *Config) private(name xml.Name) string {
 
 // SOAP arrays are declared as follows (unimportant fields ellided):
 //
-// 	<xs:complexType name="Array">
-// 	  <xs:attribute name="arrayType" type="xs:string" />
-// 	  <xs:any namespace="##any" minOccurs="0" maxOccurs="unbounded" />
-// 	</xs:complexType>
+//	<xs:complexType name="Array">
+//	  <xs:attribute name="arrayType" type="xs:string" />
+//	  <xs:any namespace="##any" minOccurs="0" maxOccurs="unbounded" />
+//	</xs:complexType>
 //
 // Then schemas that want to declare a fixed-type soap array do so like this:
 //
-// 	<xs:complexType name="IntArray">
-// 	  <xs:complexContent>
-// 	    <xs:restriction base="soapenc:Array>
-// 	      <xs:attribute ref="soapenc:arrayType" wsdl:arrayType="xs:int[]" />
-// 	    </xs:restriction>
-// 	  </xs:complexContent>
-// 	</xs:complexType>
+//	<xs:complexType name="IntArray">
+//	  <xs:complexContent>
+//	    <xs:restriction base="soapenc:Array>
+//	      <xs:attribute ref="soapenc:arrayType" wsdl:arrayType="xs:int[]" />
+//	    </xs:restriction>
+//	  </xs:complexContent>
+//	</xs:complexType>
 //
 // XML Schema is wonderful, aint it?
 func (cfg *Config) parseSOAPArrayType(s xsd.Schema, t xsd.Type) xsd.Type {
@@ -453,6 +518,9 @@ func (cfg *Config) soapArrayToSlice(s spec) spec {
 		return s
 	}
 	cfg.debugf("flattening single-element slice struct type %s to []%v", s.name, slice.Elt)
+	// Only the field's xml tag is consulted, below; any other struct
+	// tags registered by ExtraStructTags on the removed wrapper field
+	// are intentionally dropped rather than carried onto the alias.
 	tag := gen.TagKey(str.Fields.List[0], "xml")
 	xmltag := xml.Name{"", ",any"}
 
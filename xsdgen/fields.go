@@ -0,0 +1,69 @@
+package xsdgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"aqwari.net/xml/xsd"
+)
+
+// structFields builds the Go struct fields for t's (filtered) attributes
+// and elements. This is the site that consults cfg.fieldName to decide
+// between a type-derived or name-derived Go identifier, and cfg.addExtraTags
+// to add any user-registered struct tags alongside the xml tag.
+func (cfg *Config) structFields(t *xsd.ComplexType) ([]*ast.Field, error) {
+	attributes, elements := cfg.filterFields(t)
+	var fields []*ast.Field
+
+	for _, attr := range attributes {
+		typeExpr, err := cfg.expr(attr.Type)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %v", attr.Name.Local, err)
+		}
+		name := cfg.fieldName(attr.Name, cfg.typeName(xsd.XMLName(attr.Type)))
+		tag := fmt.Sprintf("%s,attr", attr.Name.Local)
+		if !attr.Required {
+			tag += ",omitempty"
+		}
+		field := &ast.Field{
+			Names: []*ast.Ident{ast.NewIdent(name)},
+			Type:  typeExpr,
+			Tag:   &ast.BasicLit{Kind: token.STRING, Value: "`xml:\"" + tag + "\"`"},
+		}
+		cfg.addExtraTags(attr.Type, FieldInfo{
+			Name:     attr.Name,
+			Attr:     true,
+			Optional: !attr.Required,
+			Type:     typeExpr,
+		}, field)
+		fields = append(fields, field)
+	}
+	for _, el := range elements {
+		typeExpr, err := cfg.expr(el.Type)
+		if err != nil {
+			return nil, fmt.Errorf("element %s: %v", el.Name.Local, err)
+		}
+		if el.Plural {
+			typeExpr = &ast.ArrayType{Elt: typeExpr}
+		}
+		name := cfg.fieldName(el.Name, cfg.typeName(xsd.XMLName(el.Type)))
+		tag := el.Name.Local
+		if el.Optional {
+			tag += ",omitempty"
+		}
+		field := &ast.Field{
+			Names: []*ast.Ident{ast.NewIdent(name)},
+			Type:  typeExpr,
+			Tag:   &ast.BasicLit{Kind: token.STRING, Value: "`xml:\"" + tag + "\"`"},
+		}
+		cfg.addExtraTags(el.Type, FieldInfo{
+			Name:     el.Name,
+			Attr:     false,
+			Optional: el.Optional,
+			Type:     typeExpr,
+		}, field)
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
@@ -0,0 +1,246 @@
+package xsdgen
+
+import (
+	"fmt"
+	"io"
+
+	"aqwari.net/xml/xsd"
+)
+
+// A PassKind identifies when in the code generation process a Pass runs.
+type PassKind int
+
+const (
+	// PreType passes run on each xsd.Type before Go source is
+	// generated for it, and may return a replacement type.
+	PreType PassKind = iota
+	// PostSpec passes run on the spec generated for each type, and
+	// may rewrite its declaration or attach additional methods.
+	PostSpec
+	// PostFile marks a pass that runs once per file, after every
+	// type in it has been generated. Nothing in this package applies
+	// PostFile passes: one added to a Pipeline is listed by
+	// DumpPipeline but never run.
+	PostFile
+)
+
+func (k PassKind) String() string {
+	switch k {
+	case PreType:
+		return "PreType"
+	case PostSpec:
+		return "PostSpec"
+	case PostFile:
+		return "PostFile"
+	default:
+		return fmt.Sprintf("PassKind(%d)", int(k))
+	}
+}
+
+// A PassCtx carries the state available to a Pass while it runs.
+type PassCtx struct {
+	Config *Config
+	Schema xsd.Schema
+	// Type is set for PreType passes.
+	Type xsd.Type
+	// Spec is set for PostSpec passes.
+	Spec spec
+	// Result, for PreType and PostSpec passes, is set by Apply to
+	// the (possibly unmodified) type or spec produced by the pass.
+	Result interface{}
+}
+
+// A Pass is a single, named step in a Config's code generation Pipeline.
+// Passes are the building blocks that HandleSOAPArrayType and
+// SOAPArrayAsSlice, and so DefaultOptions, are implemented in terms of.
+type Pass interface {
+	// Name uniquely identifies this pass within a Pipeline, and is
+	// used by RemovePass, ReplacePass, and InsertBefore/InsertAfter
+	// to locate it.
+	Name() string
+	// Kind reports when this pass runs in the generation process.
+	Kind() PassKind
+	// Apply runs the pass, storing its result in ctx.Result.
+	Apply(ctx *PassCtx) error
+}
+
+// funcPass adapts a plain function to the Pass interface.
+type funcPass struct {
+	name string
+	kind PassKind
+	fn   func(ctx *PassCtx) error
+}
+
+func (p funcPass) Name() string   { return p.name }
+func (p funcPass) Kind() PassKind { return p.kind }
+func (p funcPass) Apply(ctx *PassCtx) error {
+	return p.fn(ctx)
+}
+
+// newPass returns a Pass that runs fn, under name, at the given point in
+// the pipeline.
+func newPass(name string, kind PassKind, fn func(ctx *PassCtx) error) Pass {
+	return funcPass{name: name, kind: kind, fn: fn}
+}
+
+// A Pipeline is an ordered list of named passes that together make up a
+// Config's code generation behavior. The passes registered by
+// DefaultOptions are unchanged in behavior from prior releases; Pipeline
+// simply makes that ad-hoc chain of closures addressable, so that users
+// can compose their own extensions without wrapping every preceding
+// Option's closure.
+type Pipeline struct {
+	passes []Pass
+}
+
+func (p *Pipeline) indexOf(name string) int {
+	for i, pass := range p.passes {
+		if pass.Name() == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// AddPass appends pass to the end of the pipeline.
+func AddPass(pass Pass) Option {
+	return func(cfg *Config) Option {
+		cfg.pipeline.passes = append(cfg.pipeline.passes, pass)
+		return RemovePass(pass.Name())
+	}
+}
+
+// RemovePass removes the named pass from the pipeline, if present.
+func RemovePass(name string) Option {
+	return func(cfg *Config) Option {
+		i := cfg.pipeline.indexOf(name)
+		if i < 0 {
+			return RemovePass(name)
+		}
+		removed := cfg.pipeline.passes[i]
+		cfg.pipeline.passes = append(cfg.pipeline.passes[:i:i], cfg.pipeline.passes[i+1:]...)
+		return func(cfg *Config) Option {
+			j := i
+			if j > len(cfg.pipeline.passes) {
+				j = len(cfg.pipeline.passes)
+			}
+			cfg.insertPassAt(j, removed)
+			return RemovePass(name)
+		}
+	}
+}
+
+// ReplacePass swaps the named pass for a new one, preserving its
+// position in the pipeline.
+func ReplacePass(name string, pass Pass) Option {
+	return func(cfg *Config) Option {
+		i := cfg.pipeline.indexOf(name)
+		if i < 0 {
+			return RemovePass(pass.Name())
+		}
+		prev := cfg.pipeline.passes[i]
+		cfg.pipeline.passes[i] = pass
+		return ReplacePass(pass.Name(), prev)
+	}
+}
+
+// insertPassAt inserts pass into the pipeline at index i, shifting
+// anything already at or past i one position to the right.
+func (cfg *Config) insertPassAt(i int, pass Pass) {
+	cfg.pipeline.passes = append(cfg.pipeline.passes, nil)
+	copy(cfg.pipeline.passes[i+1:], cfg.pipeline.passes[i:])
+	cfg.pipeline.passes[i] = pass
+}
+
+// InsertBefore inserts pass immediately before the named pass. If the
+// named pass is not found, pass is appended to the end of the pipeline.
+func InsertBefore(name string, pass Pass) Option {
+	return func(cfg *Config) Option {
+		i := cfg.pipeline.indexOf(name)
+		if i < 0 {
+			i = len(cfg.pipeline.passes)
+		}
+		cfg.insertPassAt(i, pass)
+		return RemovePass(pass.Name())
+	}
+}
+
+// InsertAfter inserts pass immediately after the named pass. If the
+// named pass is not found, pass is appended to the end of the pipeline.
+func InsertAfter(name string, pass Pass) Option {
+	return func(cfg *Config) Option {
+		i := cfg.pipeline.indexOf(name)
+		if i < 0 {
+			i = len(cfg.pipeline.passes)
+		} else {
+			i++
+		}
+		cfg.insertPassAt(i, pass)
+		return RemovePass(pass.Name())
+	}
+}
+
+// DumpPipeline writes the name and kind of every pass currently
+// registered in cfg's Pipeline to w, in run order. It is intended for
+// debugging Option composition.
+func (cfg *Config) DumpPipeline(w io.Writer) {
+	for _, pass := range cfg.pipeline.passes {
+		fmt.Fprintf(w, "%s\t%s\n", pass.Kind(), pass.Name())
+	}
+}
+
+// runPreTypePasses applies every PreType pass in cfg's Pipeline, in
+// order, to t.
+func (cfg *Config) runPreTypePasses(s xsd.Schema, t xsd.Type) xsd.Type {
+	ctx := &PassCtx{Config: cfg, Schema: s, Type: t}
+	for _, pass := range cfg.pipeline.passes {
+		if pass.Kind() != PreType {
+			continue
+		}
+		ctx.Type = t
+		if err := pass.Apply(ctx); err != nil {
+			cfg.logf("pass %q: %v", pass.Name(), err)
+			continue
+		}
+		cfg.debugf("ran pass %q on %s", pass.Name(), xsd.XMLName(t).Local)
+		if nt, ok := ctx.Result.(xsd.Type); ok {
+			t = nt
+		}
+	}
+	return t
+}
+
+// runPostSpecPasses applies every PostSpec pass in cfg's Pipeline, in
+// order, to s.
+func (cfg *Config) runPostSpecPasses(schema xsd.Schema, s spec) spec {
+	ctx := &PassCtx{Config: cfg, Schema: schema}
+	for _, pass := range cfg.pipeline.passes {
+		if pass.Kind() != PostSpec {
+			continue
+		}
+		ctx.Spec = s
+		if err := pass.Apply(ctx); err != nil {
+			cfg.logf("pass %q: %v", pass.Name(), err)
+			continue
+		}
+		cfg.debugf("ran pass %q on %s", pass.Name(), s.name)
+		if ns, ok := ctx.Result.(spec); ok {
+			s = ns
+		}
+	}
+	return s
+}
+
+// soapArrayPreprocessPass is the named PreType pass that
+// HandleSOAPArrayType registers.
+var soapArrayPreprocessPass = newPass("soap-array-preprocess", PreType, func(ctx *PassCtx) error {
+	ctx.Result = ctx.Config.parseSOAPArrayType(ctx.Schema, ctx.Type)
+	return nil
+})
+
+// soapArrayPostprocessPass is the named PostSpec pass that
+// SOAPArrayAsSlice registers.
+var soapArrayPostprocessPass = newPass("soap-array-postprocess", PostSpec, func(ctx *PassCtx) error {
+	ctx.Result = ctx.Config.soapArrayToSlice(ctx.Spec)
+	return nil
+})
@@ -0,0 +1,174 @@
+package xsdgen
+
+import (
+	"encoding/xml"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"aqwari.net/xml/internal/gen"
+	"aqwari.net/xml/xsd"
+)
+
+// FieldInfo describes a single struct field while ExtraStructTags'
+// TagEmitters are being consulted for it.
+type FieldInfo struct {
+	// The element or attribute this field was generated from.
+	Name xml.Name
+	// True if this field was generated from an xsd.Attribute,
+	// rather than an xsd.Element.
+	Attr bool
+	// True if the element/attribute is optional (minOccurs="0",
+	// or a non-required attribute).
+	Optional bool
+	// The Go expression naming the field's type.
+	Type ast.Expr
+}
+
+// A TagEmitter contributes one struct tag key/value pair for a generated
+// field. Returning an empty key omits it from the field's tag.
+type TagEmitter func(t xsd.Type, info FieldInfo) (key, value string)
+
+func replaceTagEmitters(p *[]TagEmitter, fns []TagEmitter) Option {
+	return func(*Config) Option {
+		prev := *p
+		*p = fns
+		return replaceTagEmitters(p, prev)
+	}
+}
+
+// ExtraStructTags registers one or more TagEmitters, consulted in order
+// whenever xsdgen assembles a struct field, to add struct tags alongside
+// the xml tag already present on every field. Fields filtered out by
+// IgnoreElements/IgnoreAttributes are never passed to a TagEmitter.
+func ExtraStructTags(emitters ...TagEmitter) Option {
+	return func(cfg *Config) Option {
+		return replaceTagEmitters(&cfg.tagEmitters, append([]TagEmitter(nil), emitters...))(cfg)
+	}
+}
+
+// A JSONTagPolicy controls how JSONTags derives a JSON key from an
+// element or attribute's XML local name.
+type JSONTagPolicy int
+
+const (
+	// JSONCamelCase lower-cases the first letter of the XML local
+	// name, e.g. PhoneNumber becomes phoneNumber.
+	JSONCamelCase JSONTagPolicy = iota
+	// JSONSnakeCase converts the XML local name to snake_case, e.g.
+	// PhoneNumber becomes phone_number.
+	JSONSnakeCase
+	// JSONPreserveXML uses the element or attribute's XML local
+	// name, unmodified, as the JSON key.
+	JSONPreserveXML
+)
+
+// JSONTags returns a TagEmitter that adds a "json" struct tag to every
+// field, deriving the JSON key according to policy. Fields generated
+// from an optional element or attribute get the ",omitempty" option.
+func JSONTags(policy JSONTagPolicy) TagEmitter {
+	return func(t xsd.Type, info FieldInfo) (string, string) {
+		key := jsonKey(policy, info.Name.Local)
+		if info.Optional {
+			key += ",omitempty"
+		}
+		return "json", key
+	}
+}
+
+// BSONTags returns a TagEmitter that adds a "bson" struct tag to every
+// field, using the same key derivation and omitempty rules as JSONTags.
+func BSONTags(policy JSONTagPolicy) TagEmitter {
+	return func(t xsd.Type, info FieldInfo) (string, string) {
+		key := jsonKey(policy, info.Name.Local)
+		if info.Optional {
+			key += ",omitempty"
+		}
+		return "bson", key
+	}
+}
+
+// ProtoTags returns a TagEmitter that adds a "protobuf" struct tag to
+// every field. It is a stub demonstrating how to add a third-party tag
+// convention; field numbers are not assigned, since protobuf requires a
+// stable field ordering that xsdgen does not currently track.
+func ProtoTags() TagEmitter {
+	return func(t xsd.Type, info FieldInfo) (string, string) {
+		return "protobuf", "bytes,name=" + info.Name.Local
+	}
+}
+
+func jsonKey(policy JSONTagPolicy, local string) string {
+	switch policy {
+	case JSONSnakeCase:
+		return toSnakeCase(local)
+	case JSONPreserveXML:
+		return local
+	default:
+		if local == "" {
+			return local
+		}
+		r := []rune(local)
+		r[0] = toLower(r[0])
+		return string(r)
+	}
+}
+
+func toLower(r rune) rune {
+	if r >= 'A' && r <= 'Z' {
+		return r + ('a' - 'A')
+	}
+	return r
+}
+
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(toLower(r))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// addExtraTags runs cfg's registered TagEmitters for field, a struct
+// field belonging to t, and merges their output into field's existing
+// xml struct tag.
+func (cfg *Config) addExtraTags(t xsd.Type, info FieldInfo, field *ast.Field) {
+	if len(cfg.tagEmitters) == 0 {
+		return
+	}
+	existing := gen.TagKey(field, "xml")
+	tags := make(map[string]string)
+	var order []string
+	if existing != "" {
+		tags["xml"] = existing
+		order = append(order, "xml")
+	}
+	for _, emit := range cfg.tagEmitters {
+		key, value := emit(t, info)
+		if key == "" {
+			continue
+		}
+		if _, ok := tags[key]; !ok {
+			order = append(order, key)
+		}
+		tags[key] = value
+	}
+	var parts []string
+	for _, key := range order {
+		parts = append(parts, key+":"+strconv.Quote(tags[key]))
+	}
+	tag := strings.Join(parts, " ")
+	if field.Tag == nil {
+		field.Tag = new(ast.BasicLit)
+	}
+	field.Tag.Kind = token.STRING
+	field.Tag.Value = "`" + tag + "`"
+}
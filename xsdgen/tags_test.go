@@ -0,0 +1,96 @@
+package xsdgen
+
+import (
+	"encoding/xml"
+	"go/ast"
+	"go/token"
+	"strings"
+	"testing"
+
+	"aqwari.net/xml/xsd"
+)
+
+func fieldWithXMLTag(name, xmlTag string) *ast.Field {
+	return &ast.Field{
+		Names: []*ast.Ident{ast.NewIdent(name)},
+		Type:  ast.NewIdent("string"),
+		Tag:   &ast.BasicLit{Kind: token.STRING, Value: "`xml:\"" + xmlTag + "\"`"},
+	}
+}
+
+func TestAddExtraTagsRequiredField(t *testing.T) {
+	cfg := &Config{tagEmitters: []TagEmitter{JSONTags(JSONCamelCase)}}
+	field := fieldWithXMLTag("PhoneNumber", "PhoneNumber")
+
+	cfg.addExtraTags(nil, FieldInfo{Name: xml.Name{Local: "PhoneNumber"}, Optional: false}, field)
+
+	got := field.Tag.Value
+	if !strings.Contains(got, `json:"phoneNumber"`) {
+		t.Errorf("required field: expected json tag without omitempty, got %s", got)
+	}
+	if strings.Contains(got, "omitempty") {
+		t.Errorf("required field: did not expect omitempty, got %s", got)
+	}
+	if !strings.Contains(got, `xml:"PhoneNumber"`) {
+		t.Errorf("expected existing xml tag to be preserved, got %s", got)
+	}
+}
+
+func TestAddExtraTagsOptionalField(t *testing.T) {
+	cfg := &Config{tagEmitters: []TagEmitter{JSONTags(JSONCamelCase)}}
+	field := fieldWithXMLTag("MiddleName", "MiddleName,omitempty")
+
+	cfg.addExtraTags(nil, FieldInfo{Name: xml.Name{Local: "MiddleName"}, Optional: true}, field)
+
+	got := field.Tag.Value
+	if !strings.Contains(got, `json:"middleName,omitempty"`) {
+		t.Errorf("optional field: expected omitempty json tag, got %s", got)
+	}
+}
+
+func TestAddExtraTagsNoEmitters(t *testing.T) {
+	cfg := &Config{}
+	field := fieldWithXMLTag("Address", "Address")
+	before := field.Tag.Value
+
+	cfg.addExtraTags(nil, FieldInfo{Name: xml.Name{Local: "Address"}}, field)
+
+	if field.Tag.Value != before {
+		t.Errorf("expected no change with no TagEmitters registered, got %s", field.Tag.Value)
+	}
+}
+
+func TestAddExtraTagsLastWriterWins(t *testing.T) {
+	override := func(t xsd.Type, info FieldInfo) (string, string) {
+		return "json", "custom"
+	}
+	cfg := &Config{tagEmitters: []TagEmitter{JSONTags(JSONCamelCase), override}}
+	field := fieldWithXMLTag("PhoneNumber", "PhoneNumber")
+
+	cfg.addExtraTags(nil, FieldInfo{Name: xml.Name{Local: "PhoneNumber"}, Optional: false}, field)
+
+	got := field.Tag.Value
+	if !strings.Contains(got, `json:"custom"`) {
+		t.Errorf("expected the later-registered emitter's json tag to win, got %s", got)
+	}
+	if strings.Contains(got, "phoneNumber") {
+		t.Errorf("expected JSONTags' json value to be overridden, got %s", got)
+	}
+}
+
+func TestJSONKeyPolicies(t *testing.T) {
+	cases := []struct {
+		policy JSONTagPolicy
+		local  string
+		want   string
+	}{
+		{JSONCamelCase, "PhoneNumber", "phoneNumber"},
+		{JSONSnakeCase, "PhoneNumber", "phone_number"},
+		{JSONPreserveXML, "PhoneNumber", "PhoneNumber"},
+	}
+	for _, c := range cases {
+		if got := jsonKey(c.policy, c.local); got != c.want {
+			t.Errorf("jsonKey(%v, %q) = %q, want %q", c.policy, c.local, got, c.want)
+		}
+	}
+}
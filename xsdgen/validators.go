@@ -0,0 +1,285 @@
+package xsdgen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"aqwari.net/xml/internal/gen"
+	"aqwari.net/xml/xsd"
+)
+
+// digitHelpersSrc is emitted, once per Config, alongside the first
+// Validate method that uses xs:totalDigits or xs:fractionDigits.
+const digitHelpersSrc = `package p
+
+func digitCount(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			n++
+		}
+	}
+	return n
+}
+
+func fractionDigitCount(s string) int {
+	i := strings.IndexByte(s, '.')
+	if i < 0 {
+		return 0
+	}
+	return digitCount(s[i+1:])
+}
+`
+
+// A ValidatorErrorFunc builds the error message returned by a generated
+// Validate method when a facet check fails. typeName is the Go name of
+// the type being validated, facet names the XSD restriction that was
+// violated (e.g. "pattern", "enumeration"), and got is a placeholder for
+// the offending value, substituted with "%s" when building the format
+// string baked into the generated source.
+type ValidatorErrorFunc func(typeName, facet, got string) error
+
+func defaultValidatorError(typeName, facet, got string) error {
+	return fmt.Errorf("%s: value %s does not satisfy %s restriction", typeName, got, facet)
+}
+
+func replaceValidatorErrorFunc(p *ValidatorErrorFunc, fn ValidatorErrorFunc) Option {
+	return func(*Config) Option {
+		prev := *p
+		*p = fn
+		return replaceValidatorErrorFunc(p, prev)
+	}
+}
+
+// ValidatorErrorFormat overrides the text of the error returned by
+// generated Validate methods when a facet restriction is not met. fn is
+// called while generating code, with got set to "%s", and only the Error()
+// text of its result is written into the generated source; whatever type
+// of error fn returns, generated methods return a plain *errors.errorString
+// holding that text with the offending value in place of "%s". The default
+// text names the type, facet, and offending value.
+func ValidatorErrorFormat(fn ValidatorErrorFunc) Option {
+	return func(cfg *Config) Option {
+		return replaceValidatorErrorFunc(&cfg.validatorError, fn)(cfg)
+	}
+}
+
+// EmitValidators adds a post-processing step that generates a
+// Validate() error method for any type whose underlying xsd.SimpleType
+// carries facet restrictions: xs:pattern, xs:enumeration, the length
+// facets, the numeric range facets, and xs:totalDigits/xs:fractionDigits.
+// Generated UnmarshalXML methods call Validate after decoding.
+func EmitValidators() Option {
+	return func(cfg *Config) Option {
+		prev := cfg.postprocessType
+		return replacePostprocessType(&cfg.postprocessType, func(s spec) spec {
+			if prev != nil {
+				s = prev(s)
+			}
+			return cfg.addValidator(s)
+		})(cfg)
+	}
+}
+
+// errorExpr returns a Go expression, as source text, that constructs the
+// error to return when typeName fails its facet check. gotExpr is the Go
+// expression (already valid source) for the offending value.
+func (cfg *Config) errorExpr(typeName, facet, gotExpr string) string {
+	fn := cfg.validatorError
+	if fn == nil {
+		fn = defaultValidatorError
+	}
+	msg := fn(typeName, facet, "%s").Error()
+	return fmt.Sprintf("fmt.Errorf(%q, %s)", msg, gotExpr)
+}
+
+// patternVar builds the package-level `var _T_pattern = regexp.MustCompile(...)`
+// declaration for typeName's xs:pattern facet, compiling the regex once at
+// package init rather than on every Validate call.
+func patternVar(typeName, pattern string) (string, ast.Decl, error) {
+	name := "_" + typeName + "_pattern"
+	src := fmt.Sprintf("package p\n\nvar %s = regexp.MustCompile(%q)\n", name, pattern)
+	file, err := parser.ParseFile(token.NewFileSet(), name+".go", src, 0)
+	if err != nil || len(file.Decls) == 0 {
+		return "", nil, fmt.Errorf("building pattern var for %s: %v", typeName, err)
+	}
+	return name, file.Decls[0], nil
+}
+
+// addValidator emits a Validate() error method for s, if its underlying
+// xsd.SimpleType has facet restrictions. It is a no-op for types not
+// backed by a *xsd.SimpleType, or with no restrictions to enforce.
+func (cfg *Config) addValidator(s spec) spec {
+	simple, ok := s.xsdType.(*xsd.SimpleType)
+	if !ok {
+		return s
+	}
+	r := simple.Restriction
+	var checks []string
+	var extraDecls []ast.Decl
+
+	if r.Pattern != "" {
+		name, decl, err := patternVar(s.name, r.Pattern)
+		if err != nil {
+			cfg.logf("%v", err)
+		} else {
+			extraDecls = append(extraDecls, decl)
+			checks = append(checks, fmt.Sprintf(`
+				if !%s.MatchString(string(v)) {
+					return %s
+				}`, name, cfg.errorExpr(s.name, "pattern", "string(v)")))
+		}
+	}
+	if len(r.Enum) > 0 {
+		checks = append(checks, fmt.Sprintf(`
+			ok := false
+			for _, allowed := range %#v {
+				if string(v) == allowed {
+					ok = true
+					break
+				}
+			}
+			if !ok {
+				return %s
+			}`, r.Enum, cfg.errorExpr(s.name, "enumeration", "string(v)")))
+	}
+	checks = append(checks, cfg.lengthChecks(s.name, r, s.expr)...)
+	checks = append(checks, cfg.rangeChecks(s.name, r)...)
+	checks = append(checks, cfg.digitChecks(s.name, r)...)
+
+	if len(checks) == 0 {
+		return s
+	}
+
+	if (r.TotalDigits != 0 || r.FractionDigits != 0) && !cfg.digitHelpersEmitted {
+		file, err := parser.ParseFile(token.NewFileSet(), "digithelpers.go", digitHelpersSrc, 0)
+		if err != nil {
+			cfg.logf("error generating digitCount/fractionDigitCount helpers: %v", err)
+		} else {
+			extraDecls = append(extraDecls, file.Decls...)
+			cfg.digitHelpersEmitted = true
+		}
+	}
+
+	cfg.debugf("generating Validate method for %s", s.name)
+
+	validate, err := gen.Func("Validate").
+		Receiver("v "+s.name).
+		Returns("error").
+		Body("%s", strings.Join(checks, "\n")+"\nreturn nil").
+		Decl()
+	if err != nil {
+		cfg.logf("error generating Validate method of %s: %v", s.name, err)
+		return s
+	}
+
+	unmarshal, err := gen.Func("UnmarshalXML").
+		Receiver("v *"+s.name).
+		Args("d *xml.Decoder", "start xml.StartElement").
+		Returns("error").
+		Body(fmt.Sprintf(`
+			var raw %s
+			if err := d.DecodeElement(&raw, &start); err != nil {
+				return err
+			}
+			*v = %s(raw)
+			return v.Validate()
+		`, gen.ExprString(s.expr), s.name)).
+		Decl()
+	if err != nil {
+		cfg.logf("error generating UnmarshalXML method of %s: %v", s.name, err)
+		return s
+	}
+
+	s.decls = append(s.decls, extraDecls...)
+	s.methods = append(s.methods, validate, unmarshal)
+	return s
+}
+
+// isByteSlice reports whether expr is the Go representation of
+// xs:base64Binary/xs:hexBinary, []byte, whose length facets count bytes
+// rather than runes of a lossy string conversion.
+func isByteSlice(expr ast.Expr) bool {
+	array, ok := expr.(*ast.ArrayType)
+	if !ok || array.Len != nil {
+		return false
+	}
+	ident, ok := array.Elt.(*ast.Ident)
+	return ok && ident.Name == "byte"
+}
+
+func (cfg *Config) lengthChecks(typeName string, r xsd.Restriction, expr ast.Expr) []string {
+	lengthOf, gotExpr := "utf8.RuneCountInString(string(v))", "string(v)"
+	if isByteSlice(expr) {
+		lengthOf, gotExpr = "len(v)", "v"
+	}
+	if r.Length != 0 {
+		return []string{fmt.Sprintf(`
+			if n := %s; n != %d {
+				return %s
+			}`, lengthOf, r.Length, cfg.errorExpr(typeName, "length", gotExpr))}
+	}
+	var checks []string
+	if r.MinLength != 0 {
+		checks = append(checks, fmt.Sprintf(`
+			if n := %s; n < %d {
+				return %s
+			}`, lengthOf, r.MinLength, cfg.errorExpr(typeName, "minLength", gotExpr)))
+	}
+	if r.MaxLength != 0 {
+		checks = append(checks, fmt.Sprintf(`
+			if n := %s; n > %d {
+				return %s
+			}`, lengthOf, r.MaxLength, cfg.errorExpr(typeName, "maxLength", gotExpr)))
+	}
+	return checks
+}
+
+func (cfg *Config) rangeChecks(typeName string, r xsd.Restriction) []string {
+	var checks []string
+	if r.MinInclusive != "" {
+		checks = append(checks, fmt.Sprintf(`
+			if v < %s {
+				return %s
+			}`, r.MinInclusive, cfg.errorExpr(typeName, "minInclusive", "fmt.Sprint(v)")))
+	}
+	if r.MaxInclusive != "" {
+		checks = append(checks, fmt.Sprintf(`
+			if v > %s {
+				return %s
+			}`, r.MaxInclusive, cfg.errorExpr(typeName, "maxInclusive", "fmt.Sprint(v)")))
+	}
+	if r.MinExclusive != "" {
+		checks = append(checks, fmt.Sprintf(`
+			if v <= %s {
+				return %s
+			}`, r.MinExclusive, cfg.errorExpr(typeName, "minExclusive", "fmt.Sprint(v)")))
+	}
+	if r.MaxExclusive != "" {
+		checks = append(checks, fmt.Sprintf(`
+			if v >= %s {
+				return %s
+			}`, r.MaxExclusive, cfg.errorExpr(typeName, "maxExclusive", "fmt.Sprint(v)")))
+	}
+	return checks
+}
+
+func (cfg *Config) digitChecks(typeName string, r xsd.Restriction) []string {
+	var checks []string
+	if r.TotalDigits != 0 {
+		checks = append(checks, fmt.Sprintf(`
+			if n := digitCount(fmt.Sprint(v)); n > %d {
+				return %s
+			}`, r.TotalDigits, cfg.errorExpr(typeName, "totalDigits", "fmt.Sprint(v)")))
+	}
+	if r.FractionDigits != 0 {
+		checks = append(checks, fmt.Sprintf(`
+			if n := fractionDigitCount(fmt.Sprint(v)); n > %d {
+				return %s
+			}`, r.FractionDigits, cfg.errorExpr(typeName, "fractionDigits", "fmt.Sprint(v)")))
+	}
+	return checks
+}
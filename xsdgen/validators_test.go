@@ -0,0 +1,77 @@
+package xsdgen
+
+import (
+	"go/ast"
+	"go/format"
+	"go/token"
+	"strings"
+	"testing"
+
+	"aqwari.net/xml/xsd"
+)
+
+func mustPrint(t *testing.T, decl interface{}) string {
+	var buf strings.Builder
+	if err := format.Node(&buf, token.NewFileSet(), decl); err != nil {
+		t.Fatalf("printing generated decl: %v", err)
+	}
+	return buf.String()
+}
+
+func TestAddValidatorPattern(t *testing.T) {
+	cfg := &Config{}
+	s := spec{
+		name: "Code",
+		expr: ast.NewIdent("string"),
+		xsdType: &xsd.SimpleType{
+			Restriction: xsd.Restriction{Pattern: "[A-Z]{3}"},
+		},
+	}
+	s = cfg.addValidator(s)
+	if len(s.methods) == 0 {
+		t.Fatal("expected a Validate method to be generated for a pattern facet")
+	}
+	src := mustPrint(t, s.methods[0])
+	for _, want := range []string{"_Code_pattern", "MatchString"} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated Validate method missing %q:\n%s", want, src)
+		}
+	}
+	if len(s.decls) == 0 {
+		t.Fatal("expected a package-level regexp.MustCompile var to be emitted")
+	}
+	declSrc := mustPrint(t, s.decls[0])
+	if !strings.Contains(declSrc, "regexp.MustCompile") {
+		t.Errorf("expected pattern var to compile the regex once at init, got:\n%s", declSrc)
+	}
+}
+
+func TestAddValidatorEnumeration(t *testing.T) {
+	cfg := &Config{}
+	s := spec{
+		name: "Suit",
+		expr: ast.NewIdent("string"),
+		xsdType: &xsd.SimpleType{
+			Restriction: xsd.Restriction{Enum: []string{"Hearts", "Spades", "Clubs", "Diamonds"}},
+		},
+	}
+	s = cfg.addValidator(s)
+	if len(s.methods) == 0 {
+		t.Fatal("expected a Validate method to be generated for an enumeration facet")
+	}
+	src := mustPrint(t, s.methods[0])
+	for _, want := range []string{"Hearts", "Spades", "Clubs", "Diamonds"} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated Validate method missing enumeration value %q:\n%s", want, src)
+		}
+	}
+}
+
+func TestAddValidatorNoFacets(t *testing.T) {
+	cfg := &Config{}
+	s := spec{name: "Plain", xsdType: &xsd.SimpleType{}}
+	s = cfg.addValidator(s)
+	if len(s.methods) != 0 {
+		t.Errorf("expected no Validate method for a type with no facets, got %d methods", len(s.methods))
+	}
+}